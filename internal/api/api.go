@@ -6,7 +6,10 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -14,37 +17,63 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oswaldo-oliveira/ask-me-anything/internal/pow"
+	"github.com/oswaldo-oliveira/ask-me-anything/internal/responder"
 	"github.com/oswaldo-oliveira/ask-me-anything/internal/store/pgstore"
+	"github.com/oswaldo-oliveira/ask-me-anything/internal/useridentity"
+)
+
+const (
+	defaultPowDifficulty = 20
+	powChallengeTTL      = 2 * time.Minute
+	powJanitorInterval   = 1 * time.Minute
 )
 
 type apiHandler struct {
 	q           *pgstore.Queries
 	r           *chi.Mux
 	upgrader    websocket.Upgrader
-	subscribers map[string]map[*websocket.Conn]context.CancelFunc
+	subscribers map[string]map[*websocket.Conn]*subscriber
 	mu          *sync.Mutex
+	pow         *pow.Store
+	userIDs     *useridentity.Calculator
+	pool        *pgxpool.Pool
 }
 
 func (h apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.r.ServeHTTP(w, r)
 }
 
+// NewHandler builds the API's http.Handler backed by q. Prefer NewServer for a
+// handler whose /healthz endpoint can ping the database.
 func NewHandler(q *pgstore.Queries) http.Handler {
+	return newAPIHandler(q, nil)
+}
+
+func newAPIHandler(q *pgstore.Queries, pool *pgxpool.Pool) apiHandler {
+	origins := allowedOriginsFromEnv()
+
 	a := apiHandler{
 		q: q,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
-				return true
+				return originAllowed(r.Header.Get("Origin"), origins)
 			},
 		},
-		subscribers: make(map[string]map[*websocket.Conn]context.CancelFunc),
+		subscribers: make(map[string]map[*websocket.Conn]*subscriber),
 		mu:          &sync.Mutex{},
+		pow:         pow.NewStore(powDifficultyFromEnv(), powChallengeTTL),
+		userIDs:     useridentity.NewCalculator(),
+		pool:        pool,
 	}
+	a.pow.StartJanitor(context.Background(), powJanitorInterval)
 
 	r := chi.NewRouter()
+	r.Use(middleware.Heartbeat("/ping"))
 	r.Use(middleware.RequestID, middleware.Recoverer, middleware.Logger)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
+		AllowedOrigins:   corsOrigins(origins),
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -52,15 +81,19 @@ func NewHandler(q *pgstore.Queries) http.Handler {
 		MaxAge:           300,
 	}))
 
+	r.Get("/healthz", a.handleHealthz)
+	r.Get("/version", a.handleVersion)
 	r.Get("/subscribe/{room_id}", a.handleSubscribe)
 
 	r.Route("/api", func(r chi.Router) {
+		r.Get("/pow/challenge", a.handlePowChallenge)
+
 		r.Route("/rooms", func(r chi.Router) {
-			r.Post("/", a.handleCreateRoom)
+			r.With(a.pow.RequireSolution).Post("/", a.handleCreateRoom)
 			r.Get("/", a.handleGetRooms)
 
 			r.Route("/{room_id}/messages", func(r chi.Router) {
-				r.Post("/", a.handleCreateRoomMessage)
+				r.With(a.pow.RequireSolution).Post("/", a.handleCreateRoomMessage)
 				r.Get("/", a.handleGetRoomMessages)
 
 				r.Route("/{message_id}", func(r chi.Router) {
@@ -78,20 +111,72 @@ func NewHandler(q *pgstore.Queries) http.Handler {
 }
 
 const (
-	MessageKindMessageCreated = "message_created"
+	MessageKindMessageCreated         = "message_created"
+	MessageKindMessageReactionChanged = "message_reaction_changed"
+	MessageKindServerShutdown         = "server_shutdown"
 )
 
+// powDifficultyFromEnv reads AMA_POW_DIFFICULTY (leading zero bits required
+// of a solution hash), falling back to defaultPowDifficulty when unset or
+// invalid.
+func powDifficultyFromEnv() int {
+	raw := os.Getenv("AMA_POW_DIFFICULTY")
+	if raw == "" {
+		return defaultPowDifficulty
+	}
+
+	difficulty, err := strconv.Atoi(raw)
+	if err != nil || difficulty <= 0 {
+		slog.Warn("invalid AMA_POW_DIFFICULTY, using default", "value", raw, "default", defaultPowDifficulty)
+		return defaultPowDifficulty
+	}
+
+	return difficulty
+}
+
+func (h apiHandler) handlePowChallenge(w http.ResponseWriter, r *http.Request) {
+	seed, expiresAt, err := h.pow.Issue()
+	if err != nil {
+		slog.Error("failed to issue pow challenge", "error", err)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	type response struct {
+		Seed       string    `json:"seed"`
+		Difficulty int       `json:"difficulty"`
+		ExpiresAt  time.Time `json:"expires_at"`
+	}
+
+	responder.JSON(w, r, http.StatusOK, response{
+		Seed:       seed,
+		Difficulty: h.pow.Difficulty(),
+		ExpiresAt:  expiresAt,
+	})
+}
+
 type MessageMessageCreated struct {
 	ID      string `json:"id"`
 	Message string `json:"message"`
 }
 
+type MessageMessageReactionChanged struct {
+	ID    string `json:"id"`
+	Count int64  `json:"count"`
+}
+
 type Message struct {
 	Kind   string `json:"kind"`
 	Value  any    `json:"value"`
 	RoomID string `json:"-"`
 }
 
+// userID derives the anonymous user id for the requester within the room
+// the current route operates on.
+func (h apiHandler) userID(r *http.Request) string {
+	return h.userIDs.ID(r.RemoteAddr, chi.URLParam(r, "room_id"))
+}
+
 func (h apiHandler) notifyClients(msg Message) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -101,10 +186,13 @@ func (h apiHandler) notifyClients(msg Message) {
 		return
 	}
 
-	for conn, cancel := range subscribers {
-		if err := conn.WriteJSON(msg); err != nil {
-			slog.Error("failed to send message to client", "error", err)
-			cancel()
+	for conn, sub := range subscribers {
+		select {
+		case sub.send <- msg:
+		default:
+			slog.Warn("dropping slow websocket consumer", "room_id", msg.RoomID)
+			delete(subscribers, conn)
+			sub.cancel()
 		}
 	}
 }
@@ -113,44 +201,51 @@ func (h apiHandler) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	rawRoomID := chi.URLParam(r, "room_id")
 	roomID, err := uuid.Parse(rawRoomID)
 	if err != nil {
-		http.Error(w, "Invalid room ID", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_room_id", "invalid room ID")
 		return
 	}
 
 	_, err = h.q.GetRoom(r.Context(), roomID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "Room not found", http.StatusNotFound)
+			responder.Error(w, r, http.StatusNotFound, "room_not_found", "room not found")
 			return
 		}
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
 	c, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.Warn("failed to upgrade connection", "error", err)
-		http.Error(w, "failed to upgrade to ws connection", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "upgrade_failed", "failed to upgrade to ws connection")
 		return
 	}
 
-	defer c.Close()
-
 	ctx, cancel := context.WithCancel(r.Context())
+	sub := &subscriber{
+		send:   make(chan Message, wsSendBufferSize),
+		cancel: cancel,
+	}
 
 	h.mu.Lock()
 	if _, ok := h.subscribers[rawRoomID]; !ok {
-		h.subscribers[rawRoomID] = make(map[*websocket.Conn]context.CancelFunc)
+		h.subscribers[rawRoomID] = make(map[*websocket.Conn]*subscriber)
 	}
 	slog.Info("new client connected", "room_id", rawRoomID, "client_ip", r.RemoteAddr)
-	h.subscribers[rawRoomID][c] = cancel
+	h.subscribers[rawRoomID][c] = sub
 	h.mu.Unlock()
 
+	go h.readPump(c, cancel)
+	go h.writePump(ctx, c, sub)
+
 	<-ctx.Done()
 
 	h.mu.Lock()
 	delete(h.subscribers[rawRoomID], c)
 	h.mu.Unlock()
+
+	c.Close()
 }
 
 func (h apiHandler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
@@ -160,14 +255,14 @@ func (h apiHandler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 
 	var body _body
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_request_body", "invalid request body")
 		return
 	}
 
 	roomID, err := h.q.InsertRoom(r.Context(), body.Theme)
 	if err != nil {
 		slog.Error("failed to insert room", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -175,16 +270,14 @@ func (h apiHandler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 		ID string `json:"id"`
 	}
 
-	data, _ := json.Marshal(response{ID: roomID.String()})
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+	responder.JSON(w, r, http.StatusOK, response{ID: roomID.String()})
 }
 
 func (h apiHandler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
 	rooms, err := h.q.GetRooms(r.Context())
 	if err != nil {
 		slog.Error("failed to get rooms", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -206,26 +299,24 @@ func (h apiHandler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	data, _ := json.Marshal(res)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+	responder.JSON(w, r, http.StatusOK, res)
 }
 
 func (h apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Request) {
 	rawRoomID := chi.URLParam(r, "room_id")
 	roomID, err := uuid.Parse(rawRoomID)
 	if err != nil {
-		http.Error(w, "Invalid room ID", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_room_id", "invalid room ID")
 		return
 	}
 
 	_, err = h.q.GetRoom(r.Context(), roomID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "Room not found", http.StatusNotFound)
+			responder.Error(w, r, http.StatusNotFound, "room_not_found", "room not found")
 			return
 		}
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -235,14 +326,14 @@ func (h apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Reque
 
 	var body _body
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_request_body", "invalid request body")
 		return
 	}
 
 	messageID, err := h.q.InsertMessage(r.Context(), pgstore.InsertMessageParams{RoomID: roomID, Message: body.Message})
 	if err != nil {
 		slog.Error("failed to insert message", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -250,9 +341,7 @@ func (h apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Reque
 		ID string `json:"id"`
 	}
 
-	data, _ := json.Marshal(response{ID: messageID.String()})
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+	responder.JSON(w, r, http.StatusOK, response{ID: messageID.String()})
 
 	go h.notifyClients(Message{
 		Kind:   MessageKindMessageCreated,
@@ -267,18 +356,18 @@ func (h apiHandler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request
 	rawRoomID := chi.URLParam(r, "room_id")
 	roomID, err := uuid.Parse(rawRoomID)
 	if err != nil {
-		http.Error(w, "Invalid room ID", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_room_id", "invalid room ID")
 		return
 	}
 
 	messages, err := h.q.GetRoomMessages(r.Context(), roomID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "Room not found", http.StatusBadRequest)
+			responder.Error(w, r, http.StatusBadRequest, "room_not_found", "room not found")
 			return
 		}
 		slog.Error("failed to get messages", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -309,9 +398,7 @@ func (h apiHandler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request
 		})
 	}
 
-	data, _ := json.Marshal(res)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+	responder.JSON(w, r, http.StatusOK, res)
 }
 
 func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request) {
@@ -319,18 +406,18 @@ func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request)
 
 	messageID, err := uuid.Parse(rawMessageID)
 	if err != nil {
-		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_message_id", "invalid message ID")
 		return
 	}
 
 	message, err := h.q.GetMessage(r.Context(), messageID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "Message not found", http.StatusNotFound)
+			responder.Error(w, r, http.StatusNotFound, "message_not_found", "message not found")
 			return
 		}
 		slog.Error("failed to get room message", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -342,33 +429,41 @@ func (h apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request)
 		Answered      bool   `json:"answered"`
 	}
 
-	data, _ := json.Marshal(response{
+	responder.JSON(w, r, http.StatusOK, response{
 		ID:            message.ID.String(),
 		RoomID:        message.RoomID.String(),
 		Message:       message.Message,
 		ReactionCount: message.ReactionCount,
 		Answered:      message.Answered,
 	})
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
 }
 
 func (h apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request) {
 	rawMessageID := chi.URLParam(r, "message_id")
 	messageID, err := uuid.Parse(rawMessageID)
 	if err != nil {
-		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_message_id", "invalid message ID")
 		return
 	}
 
-	count, err := h.q.ReactToMessage(r.Context(), messageID)
+	_, err = h.q.GetMessage(r.Context(), messageID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "Message not found", http.StatusNotFound)
+			responder.Error(w, r, http.StatusNotFound, "message_not_found", "message not found")
 			return
 		}
-		slog.Error("failed to get room message", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		slog.Error("failed to look up message before reacting", "error", err)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	count, err := h.q.ReactToMessage(r.Context(), pgstore.ReactToMessageParams{
+		MessageID: messageID,
+		UserID:    h.userID(r),
+	})
+	if err != nil {
+		slog.Error("failed to react to message", "error", err)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
@@ -376,32 +471,77 @@ func (h apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request)
 		Count int64 `json:"count"`
 	}
 
-	data, _ := json.Marshal(response{
+	responder.JSON(w, r, http.StatusOK, response{
 		Count: count,
 	})
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+
+	go h.notifyClients(Message{
+		Kind:   MessageKindMessageReactionChanged,
+		RoomID: chi.URLParam(r, "room_id"),
+		Value: MessageMessageReactionChanged{
+			ID:    rawMessageID,
+			Count: count,
+		},
+	})
 }
 
 func (h apiHandler) handleMarkMessageAsAnswered(w http.ResponseWriter, r *http.Request) {
 	rawMessageID := chi.URLParam(r, "message_id")
 	messageID, err := uuid.Parse(rawMessageID)
 	if err != nil {
-		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		responder.Error(w, r, http.StatusBadRequest, "invalid_message_id", "invalid message ID")
 		return
 	}
 
 	err = h.q.MarkMessageAsAnswered(r.Context(), messageID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "Message not found", http.StatusNotFound)
+			responder.Error(w, r, http.StatusNotFound, "message_not_found", "message not found")
 			return
 		}
 		slog.Error("failed to mark message as answered", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
-func (h apiHandler) handleRemoveReactFromMessage(w http.ResponseWriter, r *http.Request) {}
+func (h apiHandler) handleRemoveReactFromMessage(w http.ResponseWriter, r *http.Request) {
+	rawMessageID := chi.URLParam(r, "message_id")
+	messageID, err := uuid.Parse(rawMessageID)
+	if err != nil {
+		responder.Error(w, r, http.StatusBadRequest, "invalid_message_id", "invalid message ID")
+		return
+	}
+
+	count, err := h.q.RemoveReactFromMessage(r.Context(), pgstore.RemoveReactFromMessageParams{
+		MessageID: messageID,
+		UserID:    h.userID(r),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			responder.Error(w, r, http.StatusNotFound, "reaction_not_found", "no reaction to remove")
+			return
+		}
+		slog.Error("failed to remove reaction from message", "error", err)
+		responder.Error(w, r, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	type response struct {
+		Count int64 `json:"count"`
+	}
+
+	responder.JSON(w, r, http.StatusOK, response{
+		Count: count,
+	})
+
+	go h.notifyClients(Message{
+		Kind:   MessageKindMessageReactionChanged,
+		RoomID: chi.URLParam(r, "room_id"),
+		Value: MessageMessageReactionChanged{
+			ID:    rawMessageID,
+			Count: count,
+		},
+	})
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/oswaldo-oliveira/ask-me-anything/internal/responder"
+	"github.com/oswaldo-oliveira/ask-me-anything/internal/store/pgstore"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// Server wraps http.Server with a graceful shutdown sequence that also
+// drains open websocket subscribers, making it suitable for Docker/k8s
+// liveness and readiness signals.
+type Server struct {
+	httpServer      *http.Server
+	handler         apiHandler
+	pool            *pgxpool.Pool
+	shutdownTimeout time.Duration
+}
+
+// NewServer builds a Server backed by pool, listening on addr.
+func NewServer(addr string, pool *pgxpool.Pool) *Server {
+	h := newAPIHandler(pgstore.New(pool), pool)
+
+	return &Server{
+		httpServer:      &http.Server{Addr: addr, Handler: h},
+		handler:         h,
+		pool:            pool,
+		shutdownTimeout: shutdownTimeoutFromEnv(),
+	}
+}
+
+// Run starts the HTTP server and blocks until ctx is canceled or an
+// interrupt/SIGTERM signal arrives, then drains subscribers and shuts the
+// server down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		slog.Info("http server listening", "addr", s.httpServer.Addr)
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		<-gCtx.Done()
+		slog.Info("shutting down server")
+
+		s.drainSubscribers()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		return s.httpServer.Shutdown(shutdownCtx)
+	})
+
+	return g.Wait()
+}
+
+// drainSubscribers queues a server_shutdown frame for every open websocket
+// subscriber and cancels their contexts so handleSubscribe returns. The frame
+// is not queued for a subscriber whose send buffer is full, and a queued frame
+// is not guaranteed to be written before the connection is closed.
+func (s *Server) drainSubscribers() {
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+
+	for roomID, subs := range s.handler.subscribers {
+		for _, sub := range subs {
+			select {
+			case sub.send <- Message{Kind: MessageKindServerShutdown, RoomID: roomID}:
+			default:
+			}
+			sub.cancel()
+		}
+	}
+}
+
+func shutdownTimeoutFromEnv() time.Duration {
+	raw := os.Getenv("AMA_SHUTDOWN_TIMEOUT_SECONDS")
+	if raw == "" {
+		return defaultShutdownTimeout
+	}
+
+	seconds, err := strconv.Atoi(raw)
+	if err != nil || seconds <= 0 {
+		slog.Warn("invalid AMA_SHUTDOWN_TIMEOUT_SECONDS, using default", "value", raw, "default", defaultShutdownTimeout)
+		return defaultShutdownTimeout
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+func (h apiHandler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if h.pool != nil {
+		if err := h.pool.Ping(r.Context()); err != nil {
+			slog.Error("healthz: database ping failed", "error", err)
+			responder.Error(w, r, http.StatusServiceUnavailable, "db_unavailable", "database is unreachable")
+			return
+		}
+	}
+
+	responder.JSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h apiHandler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		responder.Error(w, r, http.StatusInternalServerError, "build_info_unavailable", "build info unavailable")
+		return
+	}
+
+	type response struct {
+		GoVersion string `json:"go_version"`
+		Module    string `json:"module"`
+		Version   string `json:"version"`
+	}
+
+	responder.JSON(w, r, http.StatusOK, response{
+		GoVersion: info.GoVersion,
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+	})
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsSendBufferSize = 16
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+)
+
+// subscriber represents one open /subscribe/{room_id} connection. Writes to
+// the connection only ever happen from the writePump goroutine that owns it,
+// since gorilla/websocket allows at most one concurrent writer.
+type subscriber struct {
+	send   chan Message
+	cancel context.CancelFunc
+}
+
+// readPump drains incoming frames so pong/close control messages are
+// processed, and reaps the connection once the client goes away.
+func (h apiHandler) readPump(c *websocket.Conn, cancel context.CancelFunc) {
+	defer cancel()
+
+	c.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.SetPongHandler(func(string) error {
+		c.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump is the sole writer for c: it forwards queued messages and sends
+// periodic pings, until ctx is canceled.
+func (h apiHandler) writePump(ctx context.Context, c *websocket.Conn, sub *subscriber) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-sub.send:
+			c.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.WriteJSON(msg); err != nil {
+				slog.Error("failed to send message to client", "error", err)
+				sub.cancel()
+				return
+			}
+		case <-ticker.C:
+			c.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.WriteMessage(websocket.PingMessage, nil); err != nil {
+				sub.cancel()
+				return
+			}
+		}
+	}
+}
+
+// allowedOriginsFromEnv parses AMA_WS_ORIGINS, a comma-separated list of
+// origin patterns (wildcards allowed, e.g. "https://*.example.com"). An
+// empty result means "no origins configured": in AMA_DEBUG=1 mode that's
+// treated as permissive, otherwise every origin is rejected.
+func allowedOriginsFromEnv() []string {
+	raw := os.Getenv("AMA_WS_ORIGINS")
+	if raw == "" {
+		return nil
+	}
+
+	var origins []string
+	for _, o := range strings.Split(raw, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+func isDebugMode() bool {
+	return os.Getenv("AMA_DEBUG") == "1"
+}
+
+// originAllowed reports whether origin matches one of patterns.
+func originAllowed(origin string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return isDebugMode()
+	}
+	if origin == "" {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if matchOrigin(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsOrigins adapts the AMA_WS_ORIGINS configuration for go-chi/cors, which
+// already understands "*" wildcards in AllowedOrigins.
+func corsOrigins(patterns []string) []string {
+	if len(patterns) == 0 {
+		if isDebugMode() {
+			return []string{"https://*", "http://*"}
+		}
+		return nil
+	}
+	return patterns
+}
+
+func matchOrigin(pattern, origin string) bool {
+	if pattern == origin {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && u.Host != "" {
+		origin = u.Scheme + "://" + u.Host
+	}
+
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	re, err := regexp.Compile("^" + quoted + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(origin)
+}
@@ -0,0 +1,35 @@
+package pow
+
+import (
+	"net/http"
+
+	"github.com/oswaldo-oliveira/ask-me-anything/internal/responder"
+)
+
+// SolutionHeader is the header clients must set with their "<seed>:<nonce>"
+// solution.
+const SolutionHeader = "X-Pow-Solution"
+
+// RequireSolution is chi-compatible middleware that rejects requests missing
+// a valid proof-of-work solution with a 429.
+func (s *Store) RequireSolution(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		solution := r.Header.Get(SolutionHeader)
+		if solution == "" {
+			rejectPow(w, r, "missing "+SolutionHeader+" header")
+			return
+		}
+
+		if err := s.Verify(solution); err != nil {
+			rejectPow(w, r, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func rejectPow(w http.ResponseWriter, r *http.Request, msg string) {
+	w.Header().Set("Retry-After", "1")
+	responder.Error(w, r, http.StatusTooManyRequests, "pow_rejected", msg)
+}
@@ -0,0 +1,143 @@
+// Package pow implements a hashcash-style proof-of-work challenge used to
+// throttle anonymous clients before they can create rooms or messages.
+//
+// A client first fetches a challenge (a random seed plus a difficulty), then
+// searches for a nonce such that sha256(seed || nonce) has at least
+// difficulty leading zero bits. The solution is submitted back as
+// "<seed>:<nonce>" (both base64-encoded) and is valid exactly once.
+package pow
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrMalformedSolution = errors.New("pow: malformed solution")
+	ErrUnknownSeed       = errors.New("pow: unknown or expired seed")
+	ErrAlreadyUsed       = errors.New("pow: solution already used")
+	ErrInsufficientWork  = errors.New("pow: hash does not meet required difficulty")
+)
+
+type challenge struct {
+	expiresAt time.Time
+	used      atomic.Bool
+}
+
+// Store issues and verifies proof-of-work challenges. It is safe for
+// concurrent use.
+type Store struct {
+	difficulty int
+	ttl        time.Duration
+	challenges sync.Map // seed (base64) -> *challenge
+}
+
+// NewStore creates a Store that issues challenges requiring difficulty
+// leading zero bits, valid for ttl before they expire.
+func NewStore(difficulty int, ttl time.Duration) *Store {
+	return &Store{difficulty: difficulty, ttl: ttl}
+}
+
+// Difficulty returns the number of leading zero bits a solution must have.
+func (s *Store) Difficulty() int { return s.difficulty }
+
+// Issue creates a new challenge and returns its seed (base64-encoded) and
+// expiry.
+func (s *Store) Issue() (seed string, expiresAt time.Time, err error) {
+	raw := make([]byte, 16)
+	if _, err = rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+
+	seed = base64.StdEncoding.EncodeToString(raw)
+	expiresAt = time.Now().Add(s.ttl)
+	s.challenges.Store(seed, &challenge{expiresAt: expiresAt})
+	return seed, expiresAt, nil
+}
+
+// Verify validates a "<seed>:<nonce>" solution, consuming the seed so it
+// cannot be replayed.
+func (s *Store) Verify(solution string) error {
+	seedB64, nonceB64, ok := strings.Cut(solution, ":")
+	if !ok || seedB64 == "" || nonceB64 == "" {
+		return ErrMalformedSolution
+	}
+
+	v, ok := s.challenges.Load(seedB64)
+	if !ok {
+		return ErrUnknownSeed
+	}
+	c := v.(*challenge)
+
+	if time.Now().After(c.expiresAt) {
+		s.challenges.Delete(seedB64)
+		return ErrUnknownSeed
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(seedB64)
+	if err != nil {
+		return ErrMalformedSolution
+	}
+	nonce, err := base64.StdEncoding.DecodeString(nonceB64)
+	if err != nil {
+		return ErrMalformedSolution
+	}
+
+	sum := sha256.Sum256(append(seed, nonce...))
+	if leadingZeroBits(sum[:]) < s.difficulty {
+		return ErrInsufficientWork
+	}
+
+	if !c.used.CompareAndSwap(false, true) {
+		return ErrAlreadyUsed
+	}
+
+	return nil
+}
+
+// StartJanitor launches a background goroutine that periodically evicts
+// expired challenges. It stops when ctx is canceled.
+func (s *Store) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.challenges.Range(func(key, value any) bool {
+					if now.After(value.(*challenge).expiresAt) {
+						s.challenges.Delete(key)
+					}
+					return true
+				})
+			}
+		}
+	}()
+}
+
+func leadingZeroBits(b []byte) int {
+	count := 0
+	for _, byt := range b {
+		if byt == 0 {
+			count += 8
+			continue
+		}
+		for mask := byte(0x80); mask > 0; mask >>= 1 {
+			if byt&mask != 0 {
+				return count
+			}
+			count++
+		}
+	}
+	return count
+}
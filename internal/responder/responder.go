@@ -0,0 +1,62 @@
+// Package responder centralizes how api handlers write JSON responses, so
+// every endpoint returns the same body shape and content type.
+package responder
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// errorBody is the envelope returned for every non-2xx response.
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// JSON writes body as a JSON response with the given status code. Pass
+// ?pretty=1 to indent the output.
+func JSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	data, err := marshal(r, body)
+	if err != nil {
+		slog.Error("failed to marshal response body", "error", err)
+		Error(w, r, http.StatusInternalServerError, "encoding_failed", "failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Error writes the standard error envelope with the given status, a short
+// machine-readable code, and a human-readable message.
+func Error(w http.ResponseWriter, r *http.Request, status int, code, msg string) {
+	var body errorBody
+	body.Error.Code = code
+	body.Error.Message = msg
+	body.RequestID = middleware.GetReqID(r.Context())
+
+	data, err := marshal(r, body)
+	if err != nil {
+		slog.Error("failed to marshal error body", "error", err)
+		http.Error(w, msg, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func marshal(r *http.Request, body any) ([]byte, error) {
+	if r.URL.Query().Get("pretty") == "1" {
+		return json.MarshalIndent(body, "", "  ")
+	}
+	return json.Marshal(body)
+}
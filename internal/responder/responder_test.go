@@ -0,0 +1,71 @@
+package responder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type greeting struct {
+	Hello string `json:"hello"`
+}
+
+func TestJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	JSON(w, r, http.StatusCreated, greeting{Hello: "world"})
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatalf("content-type = %q", ct)
+	}
+
+	var got greeting
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Hello != "world" {
+		t.Fatalf("body = %+v", got)
+	}
+	if w.Body.String() != `{"hello":"world"}` {
+		t.Fatalf("JSON output should not be pretty-printed by default: %q", w.Body.String())
+	}
+}
+
+func TestJSON_Pretty(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?pretty=1", nil)
+	w := httptest.NewRecorder()
+
+	JSON(w, r, http.StatusOK, greeting{Hello: "world"})
+
+	want, err := json.MarshalIndent(greeting{Hello: "world"}, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent: %v", err)
+	}
+	if w.Body.String() != string(want) {
+		t.Fatalf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	Error(w, r, http.StatusNotFound, "room_not_found", "room not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	var body errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body.Error.Code != "room_not_found" || body.Error.Message != "room not found" {
+		t.Fatalf("body = %+v", body)
+	}
+}
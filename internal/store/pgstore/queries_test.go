@@ -0,0 +1,134 @@
+package pgstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB is an in-memory stand-in for a DBTX that reproduces, at the Go
+// level, the semantics the ReactToMessage/RemoveReactFromMessage SQL is
+// meant to implement: one reaction per (message_id, user_id), and a
+// reaction_count that only ever reflects rows actually written or removed by
+// the matching statement. It only understands the two queries exercised by
+// this test file.
+//
+// It does not enforce the message_reactions.message_id foreign key, so it
+// cannot catch constraint-violation behavior (e.g. reacting to a message_id
+// that was never inserted) — that needs a real Postgres instance, which this
+// package has no test harness for yet. The handler guards against that case
+// itself by checking message existence before calling ReactToMessage; see
+// TestReactToMessage_IdempotentPerUser for what this fake does cover.
+type fakeDB struct {
+	reactions map[string]bool
+	counts    map[uuid.UUID]int64
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{reactions: make(map[string]bool), counts: make(map[uuid.UUID]int64)}
+}
+
+func (f *fakeDB) key(messageID uuid.UUID, userID string) string {
+	return messageID.String() + ":" + userID
+}
+
+func (f *fakeDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (f *fakeDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, nil
+}
+
+func (f *fakeDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	messageID := args[0].(uuid.UUID)
+	userID := args[1].(string)
+	key := f.key(messageID, userID)
+
+	switch {
+	case strings.Contains(sql, "INSERT INTO message_reactions"):
+		if !f.reactions[key] {
+			f.reactions[key] = true
+			f.counts[messageID]++
+		}
+		return fakeRow{val: f.counts[messageID]}
+	case strings.Contains(sql, "DELETE FROM message_reactions"):
+		if !f.reactions[key] {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+		delete(f.reactions, key)
+		f.counts[messageID]--
+		return fakeRow{val: f.counts[messageID]}
+	default:
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+}
+
+type fakeRow struct {
+	val int64
+	err error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*int64) = r.val
+	return nil
+}
+
+func TestReactToMessage_IdempotentPerUser(t *testing.T) {
+	q := New(newFakeDB())
+	messageID := uuid.New()
+
+	count, err := q.ReactToMessage(context.Background(), ReactToMessageParams{MessageID: messageID, UserID: "alice"})
+	if err != nil {
+		t.Fatalf("first react: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+
+	count, err = q.ReactToMessage(context.Background(), ReactToMessageParams{MessageID: messageID, UserID: "alice"})
+	if err != nil {
+		t.Fatalf("repeat react: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("repeat react count = %d, want 1 (no-op)", count)
+	}
+
+	count, err = q.ReactToMessage(context.Background(), ReactToMessageParams{MessageID: messageID, UserID: "bob"})
+	if err != nil {
+		t.Fatalf("second user react: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("second user react count = %d, want 2", count)
+	}
+}
+
+func TestRemoveReactFromMessage(t *testing.T) {
+	q := New(newFakeDB())
+	messageID := uuid.New()
+
+	if _, err := q.ReactToMessage(context.Background(), ReactToMessageParams{MessageID: messageID, UserID: "alice"}); err != nil {
+		t.Fatalf("seed react: %v", err)
+	}
+
+	count, err := q.RemoveReactFromMessage(context.Background(), RemoveReactFromMessageParams{MessageID: messageID, UserID: "alice"})
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("count = %d, want 0", count)
+	}
+
+	if _, err := q.RemoveReactFromMessage(context.Background(), RemoveReactFromMessageParams{MessageID: messageID, UserID: "alice"}); err == nil {
+		t.Fatal("expected pgx.ErrNoRows removing a reaction that no longer exists, got nil")
+	} else if err != pgx.ErrNoRows {
+		t.Fatalf("err = %v, want pgx.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package useridentity derives stable, anonymous per-room user identifiers
+// from a request's remote address, without requiring accounts or cookies.
+package useridentity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"time"
+)
+
+// Calculator computes anonymous user IDs. IDs are stable for a given
+// (remote address, room) pair within a day, and change once the daily salt
+// rotates.
+type Calculator struct {
+	secret []byte
+}
+
+// NewCalculator creates a Calculator. The base secret can be pinned via
+// AMA_USER_ID_SALT (useful to keep IDs stable across restarts); otherwise a
+// random secret is generated for the life of the process.
+func NewCalculator() *Calculator {
+	if secret := os.Getenv("AMA_USER_ID_SALT"); secret != "" {
+		return &Calculator{secret: []byte(secret)}
+	}
+	return &Calculator{secret: randomSecret()}
+}
+
+// ID returns a stable anonymous identifier for remoteAddr within roomID,
+// rotating once per UTC day.
+func (c *Calculator) ID(remoteAddr, roomID string) string {
+	h := sha256.New()
+	h.Write(c.secret)
+	h.Write([]byte(time.Now().UTC().Format("2006-01-02")))
+	h.Write([]byte(hostOnly(remoteAddr)))
+	h.Write([]byte(roomID))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hostOnly strips the port from a host:port remote address, falling back to
+// the raw value when it doesn't look like one.
+func hostOnly(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}